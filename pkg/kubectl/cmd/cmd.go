@@ -20,10 +20,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"k8s.io/apiserver/pkg/util/flag"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/auth"
+	"k8s.io/kubernetes/pkg/kubectl/cmd/completion"
 	cmdconfig "k8s.io/kubernetes/pkg/kubectl/cmd/config"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/rollout"
 	"k8s.io/kubernetes/pkg/kubectl/cmd/set"
@@ -36,8 +38,12 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const (
-	bash_completion_func = `# call kubectl get $1,
+// bashCompletionFuncTmpl is rendered by bashCompletionFunc into the actual
+// BashCompletionFunction. resourceCommands is substituted in as the
+// pipe-separated list of "kubectl_"-joined command paths that should get
+// generic, discovery-backed resource completion - see
+// cmdutil.ResourceArgAnnotation.
+const bashCompletionFuncTmpl = `# call kubectl get $1,
 __kubectl_override_flag_list=(kubeconfig cluster user context namespace server)
 __kubectl_override_flags()
 {
@@ -97,10 +103,19 @@ __kubectl_parse_get()
     fi
 }
 
+__kubectl_resource_kinds()
+{
+    local kubectl_out
+    if kubectl_out=$(kubectl completion resource-kinds 2>/dev/null); then
+        COMPREPLY=( $( compgen -W "${kubectl_out[*]}" -- "$cur" ) )
+    fi
+}
+
 __kubectl_get_resource()
 {
     if [[ ${#nouns[@]} -eq 0 ]]; then
-        return 1
+        __kubectl_resource_kinds
+        return
     fi
     __kubectl_parse_get "${nouns[${#nouns[@]} -1]}"
 }
@@ -151,8 +166,7 @@ __kubectl_require_pod_and_container()
 
 __custom_func() {
     case ${last_command} in
-        kubectl_get | kubectl_describe | kubectl_delete | kubectl_label | kubectl_stop | kubectl_edit | kubectl_patch |\
-        kubectl_annotate | kubectl_expose | kubectl_scale | kubectl_autoscale | kubectl_taint | kubectl_rollout_*)
+        %s)
             __kubectl_get_resource
             return
             ;;
@@ -178,9 +192,37 @@ __custom_func() {
 }
 `
 
+// bashCompletionFunc renders bashCompletionFuncTmpl, generating the
+// __custom_func case list from resourceCommands instead of a hand-maintained
+// one. resourceCommands are the "kubectl_"-joined command paths of every
+// command carrying cmdutil.ResourceArgAnnotation; see resourceArgCommands.
+func bashCompletionFunc(resourceCommands []string) string {
+	return fmt.Sprintf(bashCompletionFuncTmpl, strings.Join(resourceCommands, " | "))
+}
+
+// resourceArgCommands walks cmd's command tree and returns the
+// "kubectl_"-joined path of every descendant (cmd included) annotated with
+// cmdutil.ResourceArgAnnotation, for use in the generated bash completion
+// function's __custom_func case list.
+func resourceArgCommands(cmd *cobra.Command) []string {
+	commands := []string{}
+	if cmdutil.HasResourceArgAnnotation(cmd) {
+		commands = append(commands, strings.Replace(cmd.CommandPath(), " ", "_", -1))
+	}
+	for _, child := range cmd.Commands() {
+		commands = append(commands, resourceArgCommands(child)...)
+	}
+	return commands
+}
+
+const (
 	// If you add a resource to this list, please also take a look at pkg/kubectl/kubectl.go
 	// and add a short forms entry in expandResourceShortcut() when appropriate.
-	// TODO: This should be populated using the discovery information from apiserver.
+	//
+	// Superseded for completion purposes by the discovery-backed
+	// pkg/kubectl/cmd/completion package, which reflects CRDs and aggregated
+	// APIs that can't be hardcoded here; kept for the documentation surfaces
+	// that still print it.
 	valid_resources = `Valid resource types include:
 
     * all
@@ -233,7 +275,6 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 
       Find more information at https://github.com/kubernetes/kubernetes.`),
 		Run: runHelp,
-		BashCompletionFunction: bash_completion_func,
 	}
 
 	f.BindFlags(cmds.PersistentFlags())
@@ -249,6 +290,8 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 	// From this point and forward we get warnings on flags that contain "_" separators
 	cmds.SetGlobalNormalizationFunc(flag.WarnWordSepNormalizeFunc)
 
+	completionCmd := NewCmdCompletion(f, out, "")
+
 	groups := templates.CommandGroups{
 		{
 			Message: "Basic Commands (Beginner):",
@@ -316,22 +359,79 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 			Commands: []*cobra.Command{
 				NewCmdLabel(f, out),
 				NewCmdAnnotate(f, out),
-				NewCmdCompletion(f, out, ""),
+				completionCmd,
 			},
 		},
 	}
 
-	loadedPlugins, err := plugins.NewConfigDirPluginLoader().Load()
+	// Commands that take an API resource kind as a positional argument get
+	// dynamic, discovery-backed completion for that kind (see
+	// pkg/kubectl/cmd/completion and resourceArgCommands) instead of needing
+	// a hand-maintained entry in __custom_func.
+	resourceArgCommandNames := map[string]bool{
+		"get": true, "describe": true, "delete": true, "label": true,
+		"edit": true, "patch": true, "annotate": true, "expose": true,
+		"scale": true, "autoscale": true, "taint": true,
+	}
+	for _, group := range groups {
+		for _, c := range group.Commands {
+			if resourceArgCommandNames[c.Name()] {
+				cmdutil.SetResourceArgAnnotation(c)
+			}
+			if c.Name() == "rollout" {
+				for _, sub := range c.Commands() {
+					cmdutil.SetResourceArgAnnotation(sub)
+				}
+			}
+		}
+	}
+	completionCmd.AddCommand(completion.NewCmdResourceKinds(f, out))
+
+	// kubectl discovers plugins two ways: a plugin.yaml descriptor under the
+	// plugins config directory, and (like git, and later Krew) a bare
+	// "kubectl-" prefixed executable on $PATH. Both loaders are combined
+	// into a single MultiPluginLoader - the same one "kubectl plugin list"
+	// builds in NewCmdPluginList - so a name collision between the two, or
+	// with a built-in command, is resolved and reported identically
+	// whichever path asks about it, instead of the command tree and "list"
+	// disagreeing about which plugin actually won the name.
+	builtinNames := map[string]bool{}
+	for _, group := range groups {
+		for _, c := range group.Commands {
+			builtinNames[c.Name()] = true
+		}
+	}
+	// Root commands registered below, outside of any group.
+	for _, name := range []string{"plugin", "stop", "config", "version", "api-versions", "options"} {
+		builtinNames[name] = true
+	}
+
+	pluginLoader := plugins.NewMultiPluginLoader(
+		plugins.NamedPluginLoader{Source: plugins.PluginSourceConfig, Loader: plugins.NewConfigDirPluginLoader()},
+		plugins.NamedPluginLoader{Source: plugins.PluginSourcePath, Loader: plugins.NewPathPluginLoader()},
+	)
+	pluginLoader.Builtins = builtinNames
+
+	loadedPlugins, err := pluginLoader.Load()
 	if err != nil {
 		fmt.Printf("Unable to load plugins due to: %v\n", err)
 	}
+	for _, shadowed := range pluginLoader.Shadowed() {
+		glog.V(1).Infof("Plugin %q from %s is shadowed by %s", shadowed.Plugin.Name, shadowed.Source, shadowed.ShadowedBy)
+	}
 
-	if len(loadedPlugins) > 0 {
-		pluginCmds := []*cobra.Command{}
+	pluginCmds := []*cobra.Command{}
+	pathPluginRunner := &plugins.ExecPluginRunner{}
 
-		for i := 0; i < len(loadedPlugins); i++ {
-			plugin := loadedPlugins[i]
+	for i := 0; i < len(loadedPlugins); i++ {
+		plugin := loadedPlugins[i]
 
+		switch pluginLoader.SourceOf(plugin.Name) {
+		case plugins.PluginSourcePath:
+			if pluginCmd := NewCmdForPlugin(f, plugin, pathPluginRunner, in, out, errOut); pluginCmd != nil {
+				pluginCmds = append(pluginCmds, pluginCmd)
+			}
+		default:
 			pluginCmds = append(pluginCmds, &cobra.Command{
 				Use:     plugin.Use,
 				Short:   plugin.Short,
@@ -341,18 +441,16 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 					env := os.Environ()
 
 					if plugin.Tunnel {
-						clientConfig, err := f.ClientConfig()
-						if err != nil {
-							glog.Fatal(err)
-						}
-
-						listener, err := plugins.ServePluginAPIProxy(clientConfig)
+						listener, token, err := plugins.ServePluginAPI(f)
 						if err != nil {
 							glog.Fatal(err)
 						}
 						defer listener.Close()
 
-						env = append(env, fmt.Sprintf("KUBECTL_PLUGIN_API_HOST=%s", listener.Addr()))
+						env = append(env,
+							fmt.Sprintf("KUBECTL_PLUGIN_API_HOST=%s", listener.Addr()),
+							fmt.Sprintf("KUBECTL_PLUGIN_API_TOKEN=%s", token),
+						)
 					}
 
 					err := plugin.Run(in, out, errOut, env, args...)
@@ -360,7 +458,9 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 				},
 			})
 		}
+	}
 
+	if len(pluginCmds) > 0 {
 		groups = append(groups, templates.CommandGroup{
 			Message:  "Plugins:",
 			Commands: pluginCmds,
@@ -369,9 +469,14 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 
 	groups.Add(cmds)
 
+	cmds.AddCommand(NewCmdPlugin(f, builtinNames, in, out, errOut))
+
+	stopCmd := NewCmdStop(f, out)
+	cmdutil.SetResourceArgAnnotation(stopCmd)
+
 	filters := []string{
 		"options",
-		Deprecated("kubectl", "delete", cmds, NewCmdStop(f, out)),
+		Deprecated("kubectl", "delete", cmds, stopCmd),
 	}
 	templates.ActsAsRootCommand(cmds, filters, groups...)
 
@@ -400,6 +505,8 @@ func NewKubectlCommand(f cmdutil.Factory, in io.Reader, out, errOut io.Writer) *
 	cmds.AddCommand(NewCmdApiVersions(f, out))
 	cmds.AddCommand(NewCmdOptions(out))
 
+	cmds.BashCompletionFunction = bashCompletionFunc(resourceArgCommands(cmds))
+
 	return cmds
 }
 
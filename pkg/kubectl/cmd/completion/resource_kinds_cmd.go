@@ -0,0 +1,52 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
+)
+
+// NewCmdResourceKinds creates a hidden "kubectl completion resource-kinds"
+// command. It exists purely to give the generated bash/zsh completion
+// scripts a way to ask the live (or disk-cached) discovery client for the
+// resource kind names to offer, without duplicating discovery-client
+// construction in shell.
+func NewCmdResourceKinds(f cmdutil.Factory, out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "resource-kinds",
+		Short:  "List resource kind names known to the server, for shell completion",
+		Hidden: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			// f.Discovery() already wraps a CachedDiscoveryClient backed by
+			// ~/.kube/cache/discovery/<host>/, keyed by resource version,
+			// the same on-disk cache the rest of kubectl relies on - so
+			// repeated completion invocations don't pay for a live
+			// discovery round trip on every keystroke.
+			kinds, err := ResourceKinds(f.Discovery())
+			cmdutil.CheckErr(err)
+
+			for _, name := range Names(kinds) {
+				fmt.Fprintln(out, name)
+			}
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package completion backs kubectl's dynamic, discovery-driven shell
+// completion: the list of resource kinds offered for "kubectl get <TAB>"
+// and friends comes from the server's /api and /apis endpoints instead of a
+// hand-maintained list, so CRDs and aggregated APIs complete the same way
+// built-in resources do.
+package completion
+
+import (
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/discovery"
+)
+
+// ResourceKind describes a single API resource kind as surfaced to shell
+// completion.
+type ResourceKind struct {
+	// Name is the resource's plural name, e.g. "replicasets".
+	Name string
+	// ShortNames are the resource's registered short forms, e.g. "rs".
+	ShortNames []string
+	// Namespaced is true if the resource lives inside a namespace.
+	Namespaced bool
+}
+
+// ResourceKinds returns every API resource kind known to discoveryClient -
+// built-ins, CRDs, and aggregated APIs alike. discoveryClient is expected to
+// be wrapping a disk cache (as returned by a Factory's cached discovery
+// client) so completion doesn't pay for a live discovery round trip on
+// every keystroke.
+func ResourceKinds(discoveryClient discovery.DiscoveryInterface) ([]ResourceKind, error) {
+	_, resourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil && len(resourceLists) == 0 {
+		return nil, err
+	}
+
+	byName := map[string]*ResourceKind{}
+	for _, list := range resourceLists {
+		for _, resource := range list.APIResources {
+			if strings.Contains(resource.Name, "/") {
+				// subresource, e.g. "pods/status" - not a completable noun.
+				continue
+			}
+
+			kind, ok := byName[resource.Name]
+			if !ok {
+				kind = &ResourceKind{Name: resource.Name, Namespaced: resource.Namespaced}
+				byName[resource.Name] = kind
+			}
+			kind.ShortNames = append(kind.ShortNames, resource.ShortNames...)
+		}
+	}
+
+	kinds := make([]ResourceKind, 0, len(byName))
+	for _, kind := range byName {
+		kinds = append(kinds, *kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i].Name < kinds[j].Name })
+	return kinds, nil
+}
+
+// Names flattens a list of ResourceKinds into the bare strings bash
+// completion wants: every kind's plural name plus its short names.
+func Names(kinds []ResourceKind) []string {
+	names := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		names = append(names, kind.Name)
+		names = append(names, kind.ShortNames...)
+	}
+	return names
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+)
+
+// fakeDiscoveryClient is a discovery.DiscoveryInterface that only needs to
+// answer ServerGroupsAndResources - embedding the real interface (left nil)
+// means any method ResourceKinds doesn't call panics loudly instead of
+// silently compiling into a much larger hand-written fake.
+type fakeDiscoveryClient struct {
+	discovery.DiscoveryInterface
+	resourceLists []*metav1.APIResourceList
+	err           error
+}
+
+func (f *fakeDiscoveryClient) ServerGroupsAndResources() ([]*metav1.APIGroup, []*metav1.APIResourceList, error) {
+	return nil, f.resourceLists, f.err
+}
+
+func TestResourceKinds(t *testing.T) {
+	client := &fakeDiscoveryClient{
+		resourceLists: []*metav1.APIResourceList{
+			{
+				GroupVersion: "v1",
+				APIResources: []metav1.APIResource{
+					{Name: "pods", ShortNames: []string{"po"}, Namespaced: true},
+					{Name: "pods/status"},
+					{Name: "namespaces", Namespaced: false},
+				},
+			},
+			{
+				GroupVersion: "apps/v1",
+				APIResources: []metav1.APIResource{
+					{Name: "deployments", ShortNames: []string{"deploy"}, Namespaced: true},
+					{Name: "pods", ShortNames: []string{"p"}, Namespaced: true},
+				},
+			},
+		},
+	}
+
+	kinds, err := ResourceKinds(client)
+	if err != nil {
+		t.Fatalf("ResourceKinds() returned error: %v", err)
+	}
+
+	want := []ResourceKind{
+		{Name: "deployments", ShortNames: []string{"deploy"}, Namespaced: true},
+		{Name: "namespaces", Namespaced: false},
+		{Name: "pods", ShortNames: []string{"po", "p"}, Namespaced: true},
+	}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Errorf("ResourceKinds() = %+v, want %+v", kinds, want)
+	}
+}
+
+func TestResourceKindsPropagatesErrorWithNoResources(t *testing.T) {
+	client := &fakeDiscoveryClient{err: fmt.Errorf("discovery unavailable")}
+
+	if _, err := ResourceKinds(client); err == nil {
+		t.Error("expected an error when discovery fails and returns no resources")
+	}
+}
+
+func TestResourceKindsToleratesPartialErrors(t *testing.T) {
+	client := &fakeDiscoveryClient{
+		resourceLists: []*metav1.APIResourceList{
+			{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "pods"}}},
+		},
+		err: fmt.Errorf("some-group: discovery failed"),
+	}
+
+	kinds, err := ResourceKinds(client)
+	if err != nil {
+		t.Fatalf("ResourceKinds() returned error: %v, want nil since partial results were returned", err)
+	}
+	if len(kinds) != 1 || kinds[0].Name != "pods" {
+		t.Errorf("ResourceKinds() = %+v, want the one resource list that did come back", kinds)
+	}
+}
+
+func TestNames(t *testing.T) {
+	kinds := []ResourceKind{
+		{Name: "pods", ShortNames: []string{"po", "p"}},
+		{Name: "namespaces"},
+	}
+
+	got := Names(kinds)
+	want := []string{"pods", "po", "p", "namespaces"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -30,7 +31,10 @@ import (
 	"k8s.io/kubernetes/pkg/kubectl/cmd/templates"
 	cmdutil "k8s.io/kubernetes/pkg/kubectl/cmd/util"
 	"k8s.io/kubernetes/pkg/kubectl/plugins"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+	"k8s.io/kubernetes/pkg/printers"
 	"k8s.io/kubernetes/pkg/util/i18n"
+	"k8s.io/kubernetes/pkg/util/term"
 )
 
 var (
@@ -43,32 +47,98 @@ var (
 )
 
 // NewCmdPlugin creates the command that is the top-level for plugin commands.
-func NewCmdPlugin(f cmdutil.Factory, in io.Reader, out, err io.Writer) *cobra.Command {
-	// Loads plugins and create commands for each plugin identified
-	loadedPlugins, loadErr := f.PluginLoader().Load()
-	if loadErr != nil {
-		glog.V(1).Infof("Unable to load plugins: %v", loadErr)
-	}
-
+// builtins is the set of kubectl's built-in command names, passed in by the
+// caller since NewCmdPlugin's own result isn't attached to the root yet and
+// can't discover its siblings itself.
+func NewCmdPlugin(f cmdutil.Factory, builtins map[string]bool, in io.Reader, out, err io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "plugin NAME",
 		Short: i18n.T("Runs a command-line plugin"),
 		Long:  plugin_long,
-		Run: func(cmd *cobra.Command, args []string) {
-			if len(loadedPlugins) == 0 {
-				cmdutil.CheckErr(fmt.Errorf("no plugins installed."))
-			}
-			cmdutil.DefaultSubCommandRun(err)(cmd, args)
-		},
+	}
+
+	// Resolve plugins through the same MultiPluginLoader (config + path,
+	// with built-ins taking priority) that NewCmdPluginList and cmd.go's
+	// "Plugins:" command group use, so "kubectl plugin <name>" can't
+	// disagree with either of them about which plugin won a name.
+	loader := plugins.NewMultiPluginLoader(
+		plugins.NamedPluginLoader{Source: plugins.PluginSourceConfig, Loader: plugins.NewConfigDirPluginLoader()},
+		plugins.NamedPluginLoader{Source: plugins.PluginSourcePath, Loader: plugins.NewPathPluginLoader()},
+	)
+	loader.Builtins = builtins
+
+	loadedPlugins, loadErr := loader.Load()
+	if loadErr != nil {
+		glog.V(1).Infof("Unable to load plugins: %v", loadErr)
+	}
+	for _, shadowed := range loader.Shadowed() {
+		glog.V(1).Infof("Plugin %q from %s is shadowed by %s", shadowed.Plugin.Name, shadowed.Source, shadowed.ShadowedBy)
+	}
+
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if len(loadedPlugins) == 0 {
+			cmdutil.CheckErr(fmt.Errorf("no plugins installed."))
+		}
+		cmdutil.DefaultSubCommandRun(err)(cmd, args)
 	}
 
 	if len(loadedPlugins) > 0 {
 		pluginRunner := f.PluginRunner()
 		for _, p := range loadedPlugins {
-			cmd.AddCommand(NewCmdForPlugin(f, p, pluginRunner, in, out, err))
+			if pluginCmd := NewCmdForPlugin(f, p, pluginRunner, in, out, err); pluginCmd != nil {
+				cmd.AddCommand(pluginCmd)
+			}
 		}
 	}
 
+	cmd.AddCommand(NewCmdPluginList(f, builtins, out, err))
+
+	return cmd
+}
+
+// NewCmdPluginList creates the `kubectl plugin list` command, which reports
+// every plugin kubectl can find, where each one came from, and flags any
+// that are shadowed by a built-in command or by another plugin of the same
+// name. builtins is the same set of built-in command names NewCmdPlugin
+// receives; the root's own children can't stand in for it, since the
+// "Plugins:" group's commands are registered there too.
+func NewCmdPluginList(f cmdutil.Factory, builtins map[string]bool, out, errOut io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List all installed plugins"),
+		Long: templates.LongDesc(`
+			List all plugins kubectl is able to find, both those backed by a
+			plugin.yaml descriptor under the plugins config directory and
+			those discovered as "kubectl-" prefixed executables on $PATH, and
+			report the source of each along with any names that are shadowed
+			by a built-in command or another plugin.`),
+		Run: func(cmd *cobra.Command, args []string) {
+			loader := plugins.NewMultiPluginLoader(
+				plugins.NamedPluginLoader{Source: plugins.PluginSourceConfig, Loader: plugins.NewConfigDirPluginLoader()},
+				plugins.NamedPluginLoader{Source: plugins.PluginSourcePath, Loader: plugins.NewPathPluginLoader()},
+			)
+			loader.Builtins = builtins
+
+			loaded, err := loader.Load()
+			cmdutil.CheckErr(err)
+
+			refresh := cmdutil.GetFlagBool(cmd, "refresh")
+			for _, p := range loaded {
+				if refresh && loader.SourceOf(p.Name) == plugins.PluginSourcePath {
+					if err := plugins.RefreshHelpCache(p); err != nil {
+						fmt.Fprintf(errOut, "Warning: couldn't refresh help cache for plugin %q: %v\n", p.Name, err)
+					}
+				}
+				fmt.Fprintf(out, "%s\t%s\t%s\n", p.Name, loader.SourceOf(p.Name), p.ShortDesc)
+			}
+			for _, shadowed := range loader.Shadowed() {
+				fmt.Fprintf(errOut, "Warning: plugin %q from %s is shadowed by %s\n", shadowed.Plugin.Name, shadowed.Source, shadowed.ShadowedBy)
+			}
+		},
+	}
+
+	cmd.Flags().Bool("refresh", false, "Re-run \"--help\" for every $PATH-discovered plugin and refresh its cached long description before listing.")
+
 	return cmd
 }
 
@@ -92,7 +162,8 @@ func NewCmdForPlugin(f cmdutil.Factory, plugin *plugins.Plugin, runner plugins.P
 			cfg, err := f.ClientConfig()
 			cmdutil.CheckErr(err)
 
-			runningEnvProvider := &plugins.MultiRunningEnvProvider{
+			pluginIn := in
+			envProvider := plugins.MultiRunningEnvProvider{
 				&plugins.PluginCallerEnvProvider{},
 				&plugins.OSEnvProvider{},
 				&plugins.PluginDescriptorEnvProvider{
@@ -109,13 +180,30 @@ func NewCmdForPlugin(f cmdutil.Factory, plugin *plugins.Plugin, runner plugins.P
 				},
 			}
 
+			if plugin.AcceptsResources && term.IsTerminal(in) {
+				resourceIn, err := resourceStreamForPlugin(f, cmd, plugin.AcceptedGVKs)
+				cmdutil.CheckErr(err)
+				pluginIn = resourceIn
+				envProvider = append(envProvider, plugins.StaticEnvProvider{
+					plugins.EnvInputFormat: plugins.InputFormatNDJSON,
+				})
+			}
+
+			var printer printers.ResourcePrinter
+			if len(plugin.OutputFormat) > 0 {
+				p, _, err := cmdutil.PrinterForCommand(cmd)
+				cmdutil.CheckErr(err)
+				printer = p
+			}
+
 			runningContext := plugins.RunningContext{
-				In:          in,
+				In:          pluginIn,
 				Out:         out,
 				ErrOut:      errout,
 				Args:        args,
-				EnvProvider: runningEnvProvider,
+				EnvProvider: envProvider,
 				WorkingDir:  plugin.Dir,
+				Printer:     printer,
 			}
 
 			if err := runner.Run(plugin, runningContext); err != nil {
@@ -124,6 +212,20 @@ func NewCmdForPlugin(f cmdutil.Factory, plugin *plugins.Plugin, runner plugins.P
 		},
 	}
 
+	if plugin.AcceptsResources {
+		cmd.Flags().StringSliceP("filename", "f", []string{}, "Filename, directory, or URL to files identifying the resources to pipe into the plugin.")
+		cmd.Flags().StringP("selector", "l", "", "Selector (label query) to filter the resources piped into the plugin.")
+		cmd.Flags().Bool("all-namespaces", false, "If present, pipe resources from all namespaces into the plugin.")
+	}
+
+	if len(plugin.OutputFormat) > 0 {
+		cmdutil.AddPrinterFlags(cmd)
+		// Seed --output with the descriptor's default so a plugin declaring
+		// OutputFormat gets that rendering out of the box; an explicit
+		// --output/-o on the invocation still overrides it during parsing.
+		cmd.Flags().Set("output", plugin.OutputFormat)
+	}
+
 	for _, childPlugin := range plugin.Tree {
 		cmd.AddCommand(NewCmdForPlugin(f, childPlugin, runner, in, out, errout))
 	}
@@ -131,6 +233,52 @@ func NewCmdForPlugin(f cmdutil.Factory, plugin *plugins.Plugin, runner plugins.P
 	return cmd
 }
 
+// resourceStreamForPlugin runs the filename/selector/all-namespaces flags
+// registered for a resource-accepting plugin through the ordinary resource
+// Builder and serializes the results as newline-delimited JSON, so a plugin
+// declaring AcceptsResources gets a stable stdin contract instead of
+// re-implementing resource lookup against the caller binary itself.
+func resourceStreamForPlugin(f cmdutil.Factory, cmd *cobra.Command, acceptedGVKs []string) (io.Reader, error) {
+	namespace, explicit, err := f.DefaultNamespace()
+	if err != nil {
+		return nil, err
+	}
+
+	filenames := cmdutil.GetFlagStringSlice(cmd, "filename")
+	selector := cmdutil.GetFlagString(cmd, "selector")
+	allNamespaces := cmdutil.GetFlagBool(cmd, "all-namespaces")
+
+	r := f.NewBuilder().
+		Unstructured().
+		NamespaceParam(namespace).DefaultNamespace().AllNamespaces(allNamespaces).
+		FilenameParam(explicit, &resource.FilenameOptions{Filenames: filenames}).
+		LabelSelectorParam(selector).
+		ResourceTypeOrNameArgs(allNamespaces, cmd.Flags().Args()...).
+		Flatten().
+		Do()
+
+	infos, err := r.Infos()
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer pw.Close()
+		encoder := json.NewEncoder(pw)
+		for _, info := range infos {
+			if !plugins.AcceptsGVK(acceptedGVKs, info.Object.GetObjectKind().GroupVersionKind()) {
+				continue
+			}
+			if err := encoder.Encode(info.Object); err != nil {
+				return
+			}
+		}
+	}()
+
+	return pr, nil
+}
+
 type flagsPluginEnvProvider struct {
 	cmd *cobra.Command
 }
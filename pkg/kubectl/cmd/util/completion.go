@@ -0,0 +1,39 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmdutil
+
+import "github.com/spf13/cobra"
+
+// ResourceArgAnnotation marks a command as taking an API resource kind (e.g.
+// "pods" or "deployment.apps") as one of its positional arguments. Commands
+// carrying this annotation are picked up automatically by the dynamic,
+// discovery-backed resource completion built in NewKubectlCommand, so they
+// don't need their own entry in the generated bash completion function.
+const ResourceArgAnnotation = "kubectl.kubernetes.io/resource-arg"
+
+// SetResourceArgAnnotation marks cmd as accepting a resource kind argument.
+func SetResourceArgAnnotation(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = map[string]string{}
+	}
+	cmd.Annotations[ResourceArgAnnotation] = "true"
+}
+
+// HasResourceArgAnnotation reports whether cmd was marked with SetResourceArgAnnotation.
+func HasResourceArgAnnotation(cmd *cobra.Command) bool {
+	return cmd.Annotations[ResourceArgAnnotation] == "true"
+}
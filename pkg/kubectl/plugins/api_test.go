@@ -0,0 +1,144 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery"
+	restclient "k8s.io/client-go/rest"
+)
+
+// fakePluginAPIFactory is a minimal PluginAPIFactory for exercising the
+// handlers without a real Factory, kubeconfig, or API server.
+type fakePluginAPIFactory struct {
+	namespace         string
+	namespaceExplicit bool
+	clientConfig      *restclient.Config
+}
+
+func (f *fakePluginAPIFactory) Discovery() discovery.DiscoveryInterface { return nil }
+func (f *fakePluginAPIFactory) RESTMapper() (meta.RESTMapper, error)    { return nil, nil }
+func (f *fakePluginAPIFactory) ClientConfig() (*restclient.Config, error) {
+	return f.clientConfig, nil
+}
+func (f *fakePluginAPIFactory) DefaultNamespace() (string, bool, error) {
+	return f.namespace, f.namespaceExplicit, nil
+}
+
+func TestRequireTokenRejectsMissingOrWrongToken(t *testing.T) {
+	called := false
+	handler := requireToken("the-real-token", func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/v1/handshake", nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if called {
+		t.Error("missing token: handler should not have been called")
+	}
+
+	req = httptest.NewRequest("GET", "/v1/handshake", nil)
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	rec = httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong token: got status %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if called {
+		t.Error("wrong token: handler should not have been called")
+	}
+}
+
+func TestRequireTokenAcceptsMatchingToken(t *testing.T) {
+	handler := requireToken("the-real-token", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/v1/handshake", nil)
+	req.Header.Set("Authorization", "Bearer the-real-token")
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestHandleHandshake(t *testing.T) {
+	srv := &pluginAPIServer{factory: &fakePluginAPIFactory{}}
+
+	req := httptest.NewRequest("GET", "/v1/handshake", nil)
+	rec := httptest.NewRecorder()
+	srv.handleHandshake(rec, req)
+
+	var resp HandshakeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Version != PluginAPIVersion {
+		t.Errorf("Version = %q, want %q", resp.Version, PluginAPIVersion)
+	}
+}
+
+func TestHandleDefaultNamespace(t *testing.T) {
+	srv := &pluginAPIServer{factory: &fakePluginAPIFactory{namespace: "kube-system", namespaceExplicit: true}}
+
+	req := httptest.NewRequest("GET", "/v1/namespace", nil)
+	rec := httptest.NewRecorder()
+	srv.handleDefaultNamespace(rec, req)
+
+	var resp DefaultNamespaceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Namespace != "kube-system" || !resp.Explicit {
+		t.Errorf("got %+v, want {Namespace:kube-system Explicit:true}", resp)
+	}
+}
+
+func TestHandleClientConfig(t *testing.T) {
+	srv := &pluginAPIServer{factory: &fakePluginAPIFactory{clientConfig: &restclient.Config{
+		Host:        "https://example.com",
+		APIPath:     "/api",
+		BearerToken: "abc123",
+		Insecure:    true,
+	}}}
+
+	req := httptest.NewRequest("GET", "/v1/clientconfig", nil)
+	rec := httptest.NewRecorder()
+	srv.handleClientConfig(rec, req)
+
+	var resp ClientConfigResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if resp.Host != "https://example.com" || resp.APIPath != "/api" || resp.BearerToken != "abc123" || !resp.Insecure {
+		t.Errorf("got %+v, want host/apiPath/bearerToken/insecure carried over from the resolved client config", resp)
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package client is a small Go client for the plugin API kubectl serves on
+// KUBECTL_PLUGIN_API_HOST (see pkg/kubectl/plugins.ServePluginAPI). It lets a
+// plugin ask the kubectl that invoked it for its resolved client config,
+// default namespace, discovery info, and individual objects, instead of
+// re-parsing kubeconfig and rebuilding a REST client from scratch.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/kubernetes/pkg/kubectl/plugins"
+)
+
+// EnvPluginAPIHost and EnvPluginAPIToken are the environment variables
+// kubectl sets for a plugin whose descriptor requests a Tunnel, and that
+// NewFromEnv reads to build a Client.
+const (
+	EnvPluginAPIHost  = "KUBECTL_PLUGIN_API_HOST"
+	EnvPluginAPIToken = "KUBECTL_PLUGIN_API_TOKEN"
+)
+
+// Client talks to the versioned plugin API served by the kubectl that
+// invoked the current plugin process.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// New creates a Client for the plugin API listening at host (as found in
+// KUBECTL_PLUGIN_API_HOST, e.g. "127.0.0.1:38741"), authenticating with
+// token.
+func New(host, token string) *Client {
+	return &Client{
+		baseURL: fmt.Sprintf("http://%s", host),
+		token:   token,
+		http:    &http.Client{},
+	}
+}
+
+// NewFromEnv creates a Client from the environment variables kubectl sets
+// for plugins declared with a Tunnel. It returns an error if either is
+// missing, which is the case whenever the plugin wasn't invoked with a
+// tunnel (or is being run outside of kubectl entirely).
+func NewFromEnv() (*Client, error) {
+	host := os.Getenv(EnvPluginAPIHost)
+	token := os.Getenv(EnvPluginAPIToken)
+	if len(host) == 0 || len(token) == 0 {
+		return nil, fmt.Errorf("%s and %s must be set; this plugin must be invoked by kubectl with a tunnel", EnvPluginAPIHost, EnvPluginAPIToken)
+	}
+	return New(host, token), nil
+}
+
+func (c *Client) newRequest(method, path string, query url.Values, body []byte) (*http.Request, error) {
+	u := fmt.Sprintf("%s%s", c.baseURL, path)
+	if query != nil {
+		u = fmt.Sprintf("%s?%s", u, query.Encode())
+	}
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	return req, nil
+}
+
+func (c *Client) doJSON(method, path string, query url.Values, body []byte, out interface{}) error {
+	req, err := c.newRequest(method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		errBody, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("plugin API request to %s failed with status %d: %s", path, resp.StatusCode, string(errBody))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Handshake verifies the serving kubectl speaks the same plugin API version
+// this client was built against.
+func (c *Client) Handshake() (string, error) {
+	var resp plugins.HandshakeResponse
+	if err := c.doJSON("GET", "/v1/handshake", nil, nil, &resp); err != nil {
+		return "", err
+	}
+	if resp.Version != plugins.PluginAPIVersion {
+		return resp.Version, fmt.Errorf("plugin API version mismatch: client wants %s, server speaks %s", plugins.PluginAPIVersion, resp.Version)
+	}
+	return resp.Version, nil
+}
+
+// ClientConfig returns the REST client config kubectl resolved for the
+// current invocation (kubeconfig, flags, and all).
+func (c *Client) ClientConfig() (*plugins.ClientConfigResponse, error) {
+	var resp plugins.ClientConfigResponse
+	if err := c.doJSON("GET", "/v1/clientconfig", nil, nil, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// DefaultNamespace returns the namespace kubectl would operate in, and
+// whether it was explicitly requested (via --namespace/context) as opposed
+// to defaulted.
+func (c *Client) DefaultNamespace() (string, bool, error) {
+	var resp plugins.DefaultNamespaceResponse
+	if err := c.doJSON("GET", "/v1/namespace", nil, nil, &resp); err != nil {
+		return "", false, err
+	}
+	return resp.Namespace, resp.Explicit, nil
+}
+
+// Object fetches a single object of the given kind by namespace and name.
+func (c *Client) Object(namespace string, gvk schema.GroupVersionKind, name string) (*unstructured.Unstructured, error) {
+	body, err := json.Marshal(plugins.ObjectRequest{Namespace: namespace, GVK: gvk, Name: name})
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{}
+	if err := c.doJSON("POST", "/v1/object", nil, body, obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Watch streams change events for every object of kind gvk in namespace.
+// The returned channel is closed when the underlying HTTP response ends,
+// e.g. because the returned close function is called or the serving kubectl
+// exits.
+func (c *Client) Watch(namespace string, gvk schema.GroupVersionKind) (<-chan watch.Event, func() error, error) {
+	query, err := json.Marshal(plugins.ObjectRequest{Namespace: namespace, GVK: gvk})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := c.newRequest("GET", "/v1/watch", url.Values{"request": {string(query)}}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("plugin API watch failed with status %d", resp.StatusCode)
+	}
+
+	events := make(chan watch.Event)
+	go func() {
+		defer close(events)
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			// The server can't put a concrete object straight into a
+			// watch.Event (Object is declared as the runtime.Object
+			// interface, which encoding/json has nothing to unmarshal
+			// into), so it's streamed as plugins.WatchEvent with a
+			// concrete unstructured.Unstructured payload instead.
+			var wireEvent plugins.WatchEvent
+			if err := decoder.Decode(&wireEvent); err != nil {
+				return
+			}
+			obj := wireEvent.Object
+			events <- watch.Event{Type: wireEvent.Type, Object: &obj}
+		}
+	}()
+
+	return events, resp.Body.Close, nil
+}
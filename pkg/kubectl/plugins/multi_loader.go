@@ -0,0 +1,118 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"github.com/golang/glog"
+)
+
+const (
+	// PluginSourceConfig identifies plugins discovered by ConfigDirPluginLoader,
+	// i.e. backed by a plugin.yaml descriptor.
+	PluginSourceConfig = "config"
+	// PluginSourcePath identifies plugins discovered by PathPluginLoader, i.e.
+	// a bare "kubectl-" prefixed executable on $PATH.
+	PluginSourcePath = "path"
+	// pluginSourceBuiltin is used internally to record that a discovered
+	// plugin name collided with a built-in kubectl command.
+	pluginSourceBuiltin = "builtin"
+)
+
+// NamedPluginLoader pairs a PluginLoader with a human-readable label for the
+// source it loads from, used by MultiPluginLoader to report provenance and
+// to resolve naming conflicts.
+type NamedPluginLoader struct {
+	Source string
+	Loader interface {
+		Load() ([]*Plugin, error)
+	}
+}
+
+// ShadowedPlugin describes a plugin that was discovered but suppressed
+// because something else already claimed its name.
+type ShadowedPlugin struct {
+	Plugin     *Plugin
+	Source     string
+	ShadowedBy string
+}
+
+// MultiPluginLoader composes several PluginLoaders into one, so that e.g. the
+// descriptor-based ConfigDirPluginLoader and the $PATH-scanning
+// PathPluginLoader can be combined and presented to the rest of kubectl as a
+// single loader. Loaders are consulted in the order they're given; the first
+// loader to produce a given plugin name wins, and every other occurrence
+// (including a collision with a built-in command, if Builtins is set) is
+// recorded as shadowed rather than silently dropped.
+type MultiPluginLoader struct {
+	Loaders []NamedPluginLoader
+	// Builtins, if set, is treated as an additional, highest-priority
+	// "loader" consisting of the names already taken by built-in commands.
+	Builtins map[string]bool
+
+	sources  map[string]string
+	shadowed []ShadowedPlugin
+}
+
+// NewMultiPluginLoader creates a MultiPluginLoader that consults loaders in order.
+func NewMultiPluginLoader(loaders ...NamedPluginLoader) *MultiPluginLoader {
+	return &MultiPluginLoader{Loaders: loaders}
+}
+
+// Load implements PluginLoader.
+func (m *MultiPluginLoader) Load() ([]*Plugin, error) {
+	m.sources = map[string]string{}
+	m.shadowed = nil
+
+	result := []*Plugin{}
+	for name := range m.Builtins {
+		m.sources[name] = pluginSourceBuiltin
+	}
+
+	for _, named := range m.Loaders {
+		loaded, err := named.Loader.Load()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range loaded {
+			if owner, taken := m.sources[p.Name]; taken {
+				glog.V(3).Infof("Plugin %q from %s is shadowed by %s", p.Name, named.Source, owner)
+				m.shadowed = append(m.shadowed, ShadowedPlugin{Plugin: p, Source: named.Source, ShadowedBy: owner})
+				continue
+			}
+
+			m.sources[p.Name] = named.Source
+			result = append(result, p)
+		}
+	}
+
+	return result, nil
+}
+
+// SourceOf reports which source a plugin name resolved to during the last
+// call to Load. A name in Builtins reports the internal "builtin" source
+// whether or not any loader produced it; any other name that wasn't loaded
+// at all reports the empty string.
+func (m *MultiPluginLoader) SourceOf(name string) string {
+	return m.sources[name]
+}
+
+// Shadowed returns the plugins that lost a naming conflict during the last
+// call to Load. Intended for diagnostics such as "kubectl plugin list".
+func (m *MultiPluginLoader) Shadowed() []ShadowedPlugin {
+	return m.shadowed
+}
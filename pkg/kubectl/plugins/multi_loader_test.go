@@ -0,0 +1,94 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import "testing"
+
+type fakePluginLoader struct {
+	plugins []*Plugin
+}
+
+func (l *fakePluginLoader) Load() ([]*Plugin, error) {
+	return l.plugins, nil
+}
+
+func TestMultiPluginLoaderFirstWriterWins(t *testing.T) {
+	config := &fakePluginLoader{plugins: []*Plugin{{Name: "foo"}, {Name: "bar"}}}
+	path := &fakePluginLoader{plugins: []*Plugin{{Name: "foo"}, {Name: "baz"}}}
+
+	loader := NewMultiPluginLoader(
+		NamedPluginLoader{Source: PluginSourceConfig, Loader: config},
+		NamedPluginLoader{Source: PluginSourcePath, Loader: path},
+	)
+
+	loaded, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+
+	names := map[string]bool{}
+	for _, p := range loaded {
+		names[p.Name] = true
+	}
+	if len(loaded) != 3 || !names["foo"] || !names["bar"] || !names["baz"] {
+		t.Fatalf("expected foo, bar, baz to be loaded exactly once each, got %v", names)
+	}
+
+	if source := loader.SourceOf("foo"); source != PluginSourceConfig {
+		t.Errorf("SourceOf(foo) = %q, want %q (config should win over path)", source, PluginSourceConfig)
+	}
+
+	shadowed := loader.Shadowed()
+	if len(shadowed) != 1 {
+		t.Fatalf("expected exactly one shadowed plugin, got %d: %v", len(shadowed), shadowed)
+	}
+	if shadowed[0].Plugin.Name != "foo" || shadowed[0].Source != PluginSourcePath || shadowed[0].ShadowedBy != PluginSourceConfig {
+		t.Errorf("unexpected shadowed entry: %+v", shadowed[0])
+	}
+}
+
+func TestMultiPluginLoaderBuiltinsTakePriority(t *testing.T) {
+	config := &fakePluginLoader{plugins: []*Plugin{{Name: "get"}}}
+
+	loader := NewMultiPluginLoader(
+		NamedPluginLoader{Source: PluginSourceConfig, Loader: config},
+	)
+	loader.Builtins = map[string]bool{"get": true}
+
+	loaded, err := loader.Load()
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected the plugin.yaml \"get\" plugin to be shadowed by the built-in command, got %v", loaded)
+	}
+
+	shadowed := loader.Shadowed()
+	if len(shadowed) != 1 || shadowed[0].ShadowedBy != pluginSourceBuiltin {
+		t.Fatalf("expected \"get\" to be reported as shadowed by %q, got %v", pluginSourceBuiltin, shadowed)
+	}
+}
+
+func TestMultiPluginLoaderSourceOfUnknownName(t *testing.T) {
+	loader := NewMultiPluginLoader()
+	if _, err := loader.Load(); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if source := loader.SourceOf("nonexistent"); source != "" {
+		t.Errorf("SourceOf(nonexistent) = %q, want empty string", source)
+	}
+}
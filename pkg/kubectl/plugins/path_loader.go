@@ -0,0 +1,198 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/util/homedir"
+)
+
+// pluginPrefix is the filename prefix that marks an executable on $PATH as a
+// kubectl plugin, following the convention popularized by git and later
+// adopted by kubectl plugin managers such as Krew.
+const pluginPrefix = "kubectl-"
+
+// PathPluginLoader discovers plugins by scanning the directories in $PATH for
+// executables named "kubectl-<verb>[-<verb>...]". "kubectl-foo-bar" surfaces
+// as the nested command "kubectl foo bar". Unlike ConfigDirPluginLoader, it
+// does not require a plugin.yaml descriptor.
+type PathPluginLoader struct{}
+
+// NewPathPluginLoader creates a new PathPluginLoader.
+func NewPathPluginLoader() *PathPluginLoader {
+	return &PathPluginLoader{}
+}
+
+// Load implements PluginLoader. It walks $PATH once, synthesizing a
+// *Plugin for every "kubectl-" prefixed executable it finds.
+func (l *PathPluginLoader) Load() ([]*Plugin, error) {
+	roots := map[string]*Plugin{}
+	seenFiles := map[string]bool{}
+
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if len(dir) == 0 {
+			continue
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			// $PATH commonly contains stale or unreadable entries; skip them.
+			continue
+		}
+
+		for _, file := range files {
+			if file.IsDir() || !strings.HasPrefix(file.Name(), pluginPrefix) || !isExecutableFile(file) {
+				continue
+			}
+
+			if seenFiles[file.Name()] {
+				glog.V(4).Infof("Ignoring %s: a plugin binary named %q was already found earlier in $PATH", filepath.Join(dir, file.Name()), file.Name())
+				continue
+			}
+			seenFiles[file.Name()] = true
+
+			addPathPlugin(roots, file.Name(), filepath.Join(dir, file.Name()))
+		}
+	}
+
+	plugins := make([]*Plugin, 0, len(roots))
+	for _, plugin := range roots {
+		plugins = append(plugins, plugin)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	return plugins, nil
+}
+
+// addPathPlugin threads a single "kubectl-a-b-c" binary into the roots tree,
+// creating any intermediate "a" and "a b" nodes that don't exist yet so that
+// additional dashes nest as subcommands rather than separate top-level verbs.
+func addPathPlugin(roots map[string]*Plugin, fileName, path string) {
+	segments := strings.Split(strings.TrimPrefix(fileName, pluginPrefix), "-")
+	if len(segments) == 0 || len(segments[0]) == 0 {
+		return
+	}
+
+	node, ok := roots[segments[0]]
+	if !ok {
+		node = &Plugin{Name: segments[0]}
+		roots[segments[0]] = node
+	}
+
+	for _, segment := range segments[1:] {
+		node = childPlugin(node, segment)
+	}
+
+	node.Command = path
+	node.Dir = filepath.Dir(path)
+	if len(node.ShortDesc) == 0 {
+		node.ShortDesc = fmt.Sprintf("The %q plugin, discovered on $PATH", strings.Join(segments, " "))
+	}
+	node.LongDesc = cachedHelpDesc(path)
+}
+
+// childPlugin returns the existing child of parent named name, creating and
+// appending a new one if none exists yet.
+func childPlugin(parent *Plugin, name string) *Plugin {
+	for _, child := range parent.Tree {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &Plugin{Name: name}
+	parent.Tree = append(parent.Tree, child)
+	return child
+}
+
+func isExecutableFile(file os.FileInfo) bool {
+	return file.Mode().IsRegular() && file.Mode().Perm()&0111 != 0
+}
+
+// helpCacheDir is where the (possibly stale) output of "<plugin> --help" is
+// cached, so that discovery never has to shell out to every plugin binary
+// just to build a command listing.
+func helpCacheDir() string {
+	return filepath.Join(homedir.HomeDir(), ".kube", "cache", "plugins", "help")
+}
+
+// cachedHelpDesc returns the cached "--help" output for the plugin binary at
+// path, or the empty string if there's no cache entry or it predates the
+// binary itself.
+func cachedHelpDesc(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+
+	cachePath := filepath.Join(helpCacheDir(), cacheKeyFor(path))
+	cacheInfo, err := os.Stat(cachePath)
+	if err != nil || cacheInfo.ModTime().Before(info.ModTime()) {
+		return ""
+	}
+
+	content, err := ioutil.ReadFile(cachePath)
+	if err != nil {
+		return ""
+	}
+	return string(content)
+}
+
+// refreshHelpCacheTimeout bounds how long RefreshHelpCache waits on a single
+// plugin's "--help" before giving up on it, so one hanging or misbehaving
+// binary can't stall "kubectl plugin list --refresh" for the rest.
+const refreshHelpCacheTimeout = 2 * time.Second
+
+// RefreshHelpCache runs "<plugin> --help" with a short timeout and persists
+// its output so that a later Load() can populate LongDesc without incurring
+// the cost of executing the plugin. It is invoked out-of-band by
+// "kubectl plugin list --refresh".
+func RefreshHelpCache(plugin *Plugin) error {
+	if len(plugin.Command) == 0 {
+		return fmt.Errorf("plugin %q has no command to run", plugin.Name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), refreshHelpCacheTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, plugin.Command, "--help")
+	out, _ := cmd.Output()
+
+	if err := os.MkdirAll(helpCacheDir(), 0755); err != nil {
+		return err
+	}
+	cachePath := filepath.Join(helpCacheDir(), cacheKeyFor(plugin.Command))
+	if err := ioutil.WriteFile(cachePath, out, 0644); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return os.Chtimes(cachePath, now, now)
+}
+
+func cacheKeyFor(path string) string {
+	return strings.Replace(strings.TrimPrefix(path, string(filepath.Separator)), string(filepath.Separator), "_", -1)
+}
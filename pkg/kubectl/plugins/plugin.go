@@ -0,0 +1,190 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/util/homedir"
+)
+
+// Plugin describes a single kubectl plugin, as either read from a
+// plugin.yaml descriptor (ConfigDirPluginLoader) or synthesized from a
+// "kubectl-" prefixed executable on $PATH (PathPluginLoader).
+type Plugin struct {
+	// Name is the plugin's subcommand name - "kubectl <Name>" for a
+	// top-level plugin, or the verb nested under its parent for an entry
+	// in Tree.
+	Name      string `json:"name" yaml:"name"`
+	ShortDesc string `json:"shortDesc" yaml:"shortDesc"`
+	LongDesc  string `json:"longDesc,omitempty" yaml:"longDesc,omitempty"`
+	Example   string `json:"example,omitempty" yaml:"example,omitempty"`
+	// Command is the binary (and optional leading arguments) to exec for
+	// this plugin. Required on any node without children.
+	Command string `json:"command,omitempty" yaml:"command,omitempty"`
+	// Tree holds nested plugins, letting a single plugin.yaml describe a
+	// "kubectl foo bar" style command group the same way PathPluginLoader
+	// nests "kubectl-foo-bar" executables.
+	Tree []*Plugin `json:"tree,omitempty" yaml:"tree,omitempty"`
+
+	// AcceptsResources and AcceptedGVKs opt a plugin into the ndjson
+	// resource stream described in stdin.go: when true, NewCmdForPlugin
+	// resolves -f/-l/--all-namespaces against the cluster and pipes the
+	// matching objects into the plugin's stdin instead of the terminal.
+	AcceptsResources bool     `json:"acceptsResources,omitempty" yaml:"acceptsResources,omitempty"`
+	AcceptedGVKs     []string `json:"acceptedGVKs,omitempty" yaml:"acceptedGVKs,omitempty"`
+
+	// PreRun and PostRun are additional commands ExecPluginRunner execs
+	// immediately before and after Command, in the same env and working
+	// directory as the plugin itself - e.g. a credential refresh before
+	// and cleanup after. OutputFormat, if set, captures the plugin's
+	// stdout and re-renders it through the printer NewCmdForPlugin builds
+	// from -o/--no-headers instead of streaming it through directly.
+	PreRun       string `json:"preRun,omitempty" yaml:"preRun,omitempty"`
+	PostRun      string `json:"postRun,omitempty" yaml:"postRun,omitempty"`
+	OutputFormat string `json:"outputFormat,omitempty" yaml:"outputFormat,omitempty"`
+
+	// Use, Short, Long, and Tunnel describe a plugin invoked through the
+	// older tunnel-calling convention in cmd.go, where the plugin process
+	// is handed a short-lived plugin API endpoint (ServePluginAPI) instead
+	// of being wired up as its own cobra command tree. They're populated
+	// from the same plugin.yaml as the fields above.
+	Use    string `json:"use,omitempty" yaml:"use,omitempty"`
+	Short  string `json:"short,omitempty" yaml:"short,omitempty"`
+	Long   string `json:"long,omitempty" yaml:"long,omitempty"`
+	Tunnel bool   `json:"tunnel,omitempty" yaml:"tunnel,omitempty"`
+
+	// Dir is the directory RunningContext.WorkingDir is set to when the
+	// plugin runs. It's derived from where the plugin was discovered, not
+	// read from the descriptor.
+	Dir string `json:"-" yaml:"-"`
+}
+
+// IsValid reports whether a plugin (and, recursively, every plugin in its
+// Tree) has enough information to be turned into a runnable command: a
+// name, and either something to exec or nested children that do.
+func (p *Plugin) IsValid() bool {
+	if len(p.Name) == 0 {
+		return false
+	}
+	if len(p.Command) == 0 && len(p.Tree) == 0 {
+		return false
+	}
+	for _, child := range p.Tree {
+		if !child.IsValid() {
+			return false
+		}
+	}
+	return true
+}
+
+// Run execs a tunnel-style plugin's Command directly, for the cmd.go
+// calling convention that doesn't go through PluginRunner.
+func (p *Plugin) Run(in io.Reader, out, errOut io.Writer, env []string, args ...string) error {
+	if len(p.Command) == 0 {
+		return fmt.Errorf("plugin %q has no command to run", p.Name)
+	}
+
+	command := strings.Split(os.ExpandEnv(p.Command), " ")
+	cmdArgs := append(append([]string{}, command[1:]...), args...)
+
+	cmd := exec.Command(command[0], cmdArgs...)
+	cmd.Stdin = in
+	cmd.Stdout = out
+	cmd.Stderr = errOut
+	cmd.Env = env
+	cmd.Dir = p.Dir
+
+	return cmd.Run()
+}
+
+// pluginDescriptorFileName is the descriptor ConfigDirPluginLoader looks
+// for in each of its plugin directories.
+const pluginDescriptorFileName = "plugin.yaml"
+
+// ConfigDirPluginLoader discovers plugins from a directory of
+// "<plugin-name>/plugin.yaml" descriptors, one subdirectory per plugin.
+type ConfigDirPluginLoader struct {
+	Dir string
+}
+
+// NewConfigDirPluginLoader creates a ConfigDirPluginLoader rooted at
+// ~/.kube/plugins, the same directory kubectl has always used for
+// plugin.yaml-backed plugins.
+func NewConfigDirPluginLoader() *ConfigDirPluginLoader {
+	return &ConfigDirPluginLoader{Dir: filepath.Join(homedir.HomeDir(), ".kube", "plugins")}
+}
+
+// Load implements PluginLoader. A missing plugins directory is not an
+// error - it just means no config-backed plugins are installed.
+func (l *ConfigDirPluginLoader) Load() ([]*Plugin, error) {
+	entries, err := ioutil.ReadDir(l.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	result := []*Plugin{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		pluginDir := filepath.Join(l.Dir, entry.Name())
+		descriptorPath := filepath.Join(pluginDir, pluginDescriptorFileName)
+		data, err := ioutil.ReadFile(descriptorPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("could not read %s: %v", descriptorPath, err)
+		}
+
+		plugin := &Plugin{}
+		if err := yaml.Unmarshal(data, plugin); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", descriptorPath, err)
+		}
+		if len(plugin.Name) == 0 {
+			plugin.Name = entry.Name()
+		}
+		setPluginDir(plugin, pluginDir)
+		result = append(result, plugin)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// setPluginDir stamps dir onto plugin and, recursively, onto every plugin
+// in its Tree, so a nested plugin still resolves relative Command paths
+// and WorkingDir against the descriptor's own directory.
+func setPluginDir(plugin *Plugin, dir string) {
+	plugin.Dir = dir
+	for _, child := range plugin.Tree {
+		setPluginDir(child, dir)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package plugins
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
@@ -24,6 +25,9 @@ import (
 	"strings"
 
 	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/kubernetes/pkg/printers"
 )
 
 // PluginRunner is capable of running a plugin in a given running context.
@@ -41,14 +45,28 @@ type RunningContext struct {
 	Args        []string
 	EnvProvider RunningEnvProvider
 	WorkingDir  string
+	// Printer, if set, is used to re-render a plugin's captured stdout
+	// instead of streaming it straight through. It's only consulted when
+	// the plugin descriptor declares an OutputFormat, so that plugins
+	// participate in the same -o/--no-headers handling as built-in
+	// commands instead of each one reimplementing it.
+	Printer printers.ResourcePrinter
 }
 
 // ExecPluginRunner is a PluginRunner that uses Go's os/exec to run plugins.
 type ExecPluginRunner struct{}
 
-// Run takes a given plugin and runs it in a given context using os/exec, returning
-// any error found while running.
+// Run takes a given plugin and runs it in a given context using os/exec,
+// returning any error found while running. A descriptor-declared PreRun
+// hook runs before the plugin's Command and a PostRun hook after, both in
+// the same working directory and environment as the plugin itself; if the
+// plugin declares an OutputFormat, its stdout is captured and decoded
+// instead of being streamed directly, then re-printed through ctx.Printer.
 func (r *ExecPluginRunner) Run(plugin *Plugin, ctx RunningContext) error {
+	if err := r.runHook("PreRun", plugin.PreRun, ctx); err != nil {
+		return err
+	}
+
 	command := strings.Split(os.ExpandEnv(plugin.Command), " ")
 	base := command[0]
 	args := []string{}
@@ -60,9 +78,16 @@ func (r *ExecPluginRunner) Run(plugin *Plugin, ctx RunningContext) error {
 	cmd := exec.Command(base, args...)
 
 	cmd.Stdin = ctx.In
-	cmd.Stdout = ctx.Out
 	cmd.Stderr = ctx.ErrOut
 
+	var captured bytes.Buffer
+	capturing := len(plugin.OutputFormat) > 0 && ctx.Printer != nil
+	if capturing {
+		cmd.Stdout = &captured
+	} else {
+		cmd.Stdout = ctx.Out
+	}
+
 	env, err := ctx.EnvProvider.Env()
 	if err != nil {
 		return err
@@ -71,7 +96,79 @@ func (r *ExecPluginRunner) Run(plugin *Plugin, ctx RunningContext) error {
 	cmd.Dir = ctx.WorkingDir
 
 	glog.V(9).Infof("Running plugin %q as base command %q with args %v", plugin.Name, base, args)
-	return cmd.Run()
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+
+	if capturing {
+		if err := printCapturedOutput(plugin.OutputFormat, captured.Bytes(), ctx.Printer, ctx.Out); err != nil {
+			return fmt.Errorf("plugin %q produced output that couldn't be printed as %s: %v", plugin.Name, plugin.OutputFormat, err)
+		}
+	}
+
+	return r.runHook("PostRun", plugin.PostRun, ctx)
+}
+
+// runHook runs an additional command declared in the plugin descriptor
+// (PreRun or PostRun), in the same env and working directory as the
+// plugin's own Command, so patterns like credential refresh before exec and
+// cleanup after don't need to reimplement env parsing themselves. A blank
+// hook command is a no-op. Hooks never see ctx.In: for an AcceptsResources
+// plugin it's a one-shot pipe streaming the ndjson resource list, and
+// running a hook against it would drain or partially consume that stream
+// before the plugin itself ever starts.
+func (r *ExecPluginRunner) runHook(name, hookCommand string, ctx RunningContext) error {
+	if len(hookCommand) == 0 {
+		return nil
+	}
+
+	command := strings.Split(os.ExpandEnv(hookCommand), " ")
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stdin = nil
+	cmd.Stdout = ctx.Out
+	cmd.Stderr = ctx.ErrOut
+
+	env, err := ctx.EnvProvider.Env()
+	if err != nil {
+		return err
+	}
+	cmd.Env = env
+	cmd.Dir = ctx.WorkingDir
+
+	glog.V(9).Infof("Running %s hook %q", name, hookCommand)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook %q failed: %v", name, hookCommand, err)
+	}
+	return nil
+}
+
+// printCapturedOutput decodes a plugin's captured stdout - expected to be a
+// single object or list encoded as yaml or json, matching OutputFormat -
+// into runtime.Objects and re-renders each through printer, so a plugin's
+// output goes through the same printer built-in commands use instead of the
+// plugin choosing its own -o wide/json/jsonpath formatting.
+func printCapturedOutput(outputFormat string, data []byte, printer printers.ResourcePrinter, out io.Writer) error {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: raw}
+
+	items, isList := raw["items"].([]interface{})
+	if !isList {
+		return printer.PrintObj(obj, out)
+	}
+
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("list item was not an object: %T", item)
+		}
+		if err := printer.PrintObj(&unstructured.Unstructured{Object: fields}, out); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // RunningEnvProvider provides the environment (with entries in the KEY=VALUE form)
@@ -137,3 +234,16 @@ type EmptyEnvProvider struct{}
 func (p *EmptyEnvProvider) Env() ([]string, error) {
 	return []string{}, nil
 }
+
+// StaticEnvProvider is a RunningEnvProvider backed by a fixed set of
+// KEY=VALUE pairs, for callers that already know the exact env entries they
+// want to contribute rather than deriving them from a struct or the OS.
+type StaticEnvProvider map[string]string
+
+func (p StaticEnvProvider) Env() ([]string, error) {
+	env := make([]string, 0, len(p))
+	for k, v := range p {
+		env = append(env, fmt.Sprintf("%s=%s", k, v))
+	}
+	return env, nil
+}
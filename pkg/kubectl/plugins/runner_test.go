@@ -0,0 +1,134 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// fakePrinter is a printers.ResourcePrinter that records every object it's
+// asked to print, in order, instead of rendering anything.
+type fakePrinter struct {
+	printed []runtime.Object
+}
+
+func (p *fakePrinter) PrintObj(obj runtime.Object, w io.Writer) error {
+	p.printed = append(p.printed, obj)
+	return nil
+}
+
+func TestExecPluginRunnerRunsHooksAroundCommand(t *testing.T) {
+	var out bytes.Buffer
+	plugin := &Plugin{
+		Name:    "echoer",
+		PreRun:  "echo PRE",
+		Command: "echo MAIN",
+		PostRun: "echo POST",
+	}
+	ctx := RunningContext{
+		In:          bytes.NewBufferString("this would be drained if a hook read it"),
+		Out:         &out,
+		ErrOut:      &bytes.Buffer{},
+		EnvProvider: &EmptyEnvProvider{},
+	}
+
+	runner := &ExecPluginRunner{}
+	if err := runner.Run(plugin, ctx); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+
+	want := "PRE\nMAIN\nPOST\n"
+	if out.String() != want {
+		t.Errorf("Run() wrote %q, want %q (PreRun, then Command, then PostRun)", out.String(), want)
+	}
+}
+
+func TestExecPluginRunnerSkipsMissingHooks(t *testing.T) {
+	var out bytes.Buffer
+	plugin := &Plugin{Name: "echoer", Command: "echo MAIN"}
+	ctx := RunningContext{Out: &out, ErrOut: &bytes.Buffer{}, EnvProvider: &EmptyEnvProvider{}}
+
+	runner := &ExecPluginRunner{}
+	if err := runner.Run(plugin, ctx); err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if want := "MAIN\n"; out.String() != want {
+		t.Errorf("Run() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestPrintCapturedOutputSingleObject(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		data string
+	}{
+		{name: "yaml", data: "kind: Pod\nmetadata:\n  name: foo\n"},
+		{name: "json", data: `{"kind":"Pod","metadata":{"name":"foo"}}`},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			printer := &fakePrinter{}
+			var out bytes.Buffer
+
+			if err := printCapturedOutput("yaml", []byte(test.data), printer, &out); err != nil {
+				t.Fatalf("printCapturedOutput() returned error: %v", err)
+			}
+
+			if len(printer.printed) != 1 {
+				t.Fatalf("printed %d objects, want 1", len(printer.printed))
+			}
+			obj, ok := printer.printed[0].(*unstructured.Unstructured)
+			if !ok {
+				t.Fatalf("printed object was %T, want *unstructured.Unstructured", printer.printed[0])
+			}
+			if obj.GetName() != "foo" || obj.GetKind() != "Pod" {
+				t.Errorf("printed object = %+v, want name=foo kind=Pod", obj.Object)
+			}
+		})
+	}
+}
+
+func TestPrintCapturedOutputList(t *testing.T) {
+	data := `{"items":[{"kind":"Pod","metadata":{"name":"a"}},{"kind":"Pod","metadata":{"name":"b"}}]}`
+	printer := &fakePrinter{}
+	var out bytes.Buffer
+
+	if err := printCapturedOutput("json", []byte(data), printer, &out); err != nil {
+		t.Fatalf("printCapturedOutput() returned error: %v", err)
+	}
+
+	if len(printer.printed) != 2 {
+		t.Fatalf("printed %d objects, want 2", len(printer.printed))
+	}
+	for i, name := range []string{"a", "b"} {
+		obj, ok := printer.printed[i].(*unstructured.Unstructured)
+		if !ok || obj.GetName() != name {
+			t.Errorf("printed[%d] = %+v, want name=%s", i, printer.printed[i], name)
+		}
+	}
+}
+
+func TestPrintCapturedOutputInvalidData(t *testing.T) {
+	printer := &fakePrinter{}
+	if err := printCapturedOutput("yaml", []byte("not: [valid"), printer, &bytes.Buffer{}); err == nil {
+		t.Error("expected an error decoding malformed captured output")
+	}
+}
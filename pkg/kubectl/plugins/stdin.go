@@ -0,0 +1,44 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import "k8s.io/apimachinery/pkg/runtime/schema"
+
+// EnvInputFormat is the environment variable kubectl sets for a plugin
+// declared with AcceptsResources, telling it how to interpret its stdin.
+const EnvInputFormat = "KUBECTL_PLUGINS_INPUT_FORMAT"
+
+// InputFormatNDJSON is the only input format kubectl currently knows how to
+// produce on a plugin's stdin: one JSON-encoded object per line.
+const InputFormatNDJSON = "ndjson"
+
+// AcceptsGVK reports whether gvk is one a plugin declaring the given
+// accepted kinds should receive. An empty list or a single "*" entry accepts
+// everything; otherwise gvk must match one of the entries either by its
+// full "group/version, Kind=Kind" string or by bare Kind, so a descriptor
+// can say just "Pod" without spelling out the core group and version.
+func AcceptsGVK(accepted []string, gvk schema.GroupVersionKind) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	for _, a := range accepted {
+		if a == "*" || a == gvk.String() || a == gvk.Kind {
+			return true
+		}
+	}
+	return false
+}
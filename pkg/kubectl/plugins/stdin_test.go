@@ -0,0 +1,50 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plugins
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAcceptsGVK(t *testing.T) {
+	pod := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	deployment := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	tests := []struct {
+		name     string
+		accepted []string
+		gvk      schema.GroupVersionKind
+		want     bool
+	}{
+		{name: "empty list accepts everything", accepted: nil, gvk: pod, want: true},
+		{name: "wildcard accepts everything", accepted: []string{"*"}, gvk: deployment, want: true},
+		{name: "bare kind matches", accepted: []string{"Pod"}, gvk: pod, want: true},
+		{name: "bare kind does not match other kinds", accepted: []string{"Pod"}, gvk: deployment, want: false},
+		{name: "full gvk string matches", accepted: []string{deployment.String()}, gvk: deployment, want: true},
+		{name: "no match against an unrelated list", accepted: []string{"Service", "ConfigMap"}, gvk: pod, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := AcceptsGVK(test.accepted, test.gvk); got != test.want {
+				t.Errorf("AcceptsGVK(%v, %v) = %v, want %v", test.accepted, test.gvk, got, test.want)
+			}
+		})
+	}
+}